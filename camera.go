@@ -0,0 +1,57 @@
+/*
+ *          Copyright 2020, Vitali Baumtrok.
+ * Distributed under the Boost Software License, Version 1.0.
+ *     (See accompanying file LICENSE or copy at
+ *        http://www.boost.org/LICENSE_1_0.txt)
+ */
+
+package main
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/vbsw/shaders"
+)
+
+// Camera holds a position in world space and a zoom factor. Its
+// projection matrix is an orthographic projection centered on that
+// position, rebuilt whenever the viewport it belongs to changes size.
+type Camera struct {
+	X, Y, Zoom float32
+	Projection mgl32.Mat4
+}
+
+// NewCamera creates a camera looking at (x, y) with the given zoom.
+// A zoom of 1 shows one world unit per pixel.
+func NewCamera(x, y, zoom float32) *Camera {
+	return &Camera{X: x, Y: y, Zoom: zoom}
+}
+
+func (c *Camera) updateProjection(viewWidth, viewHeight float32) {
+	halfWidth := viewWidth / (2 * c.Zoom)
+	halfHeight := viewHeight / (2 * c.Zoom)
+	c.Projection = mgl32.Ortho2D(c.X-halfWidth, c.X+halfWidth, c.Y-halfHeight, c.Y+halfHeight)
+}
+
+// Viewport maps a camera onto a rectangular region of the window, in
+// window pixel coordinates (origin bottom-left, as expected by
+// gl.Viewport).
+type Viewport struct {
+	X, Y, W, H int32
+	Cam        *Camera
+}
+
+// NewViewport creates a viewport rendering Cam into the rectangle
+// (x, y, w, h) of the window.
+func NewViewport(x, y, w, h int32, cam *Camera) *Viewport {
+	return &Viewport{X: x, Y: y, W: w, H: h, Cam: cam}
+}
+
+// bind activates the viewport's region and uploads its camera's
+// projection matrix. Call once per viewport before issuing draw
+// calls for that pass.
+func (v *Viewport) bind(shader *shaders.Shader) {
+	gl.Viewport(v.X, v.Y, v.W, v.H)
+	v.Cam.updateProjection(float32(v.W), float32(v.H))
+	gl.UniformMatrix4fv(shader.ProjectionLocation, 1, false, &v.Cam.Projection[0])
+}
@@ -0,0 +1,217 @@
+/*
+ *          Copyright 2020, Vitali Baumtrok.
+ * Distributed under the Boost Software License, Version 1.0.
+ *     (See accompanying file LICENSE or copy at
+ *        http://www.boost.org/LICENSE_1_0.txt)
+ */
+
+package main
+
+import (
+	"errors"
+	"image"
+	"image/png"
+	"os"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+const postVertexShaderSource = `
+#version 330 core
+layout(location = 0) in vec2 position;
+layout(location = 1) in vec2 texCoord;
+out vec2 fragTexCoord;
+void main() {
+	fragTexCoord = texCoord;
+	gl_Position = vec4(position, 0.0, 1.0);
+}
+` + "\x00"
+
+const postFragmentShaderSource = `
+#version 330 core
+in vec2 fragTexCoord;
+out vec4 fragColor;
+uniform sampler2D screenTexture;
+uniform float scanlineStrength;
+void main() {
+	vec4 color = texture(screenTexture, fragTexCoord);
+	float scanline = sin(fragTexCoord.y * 360.0 * 3.14159) * scanlineStrength;
+	fragColor = vec4(color.rgb - scanline, color.a);
+}
+` + "\x00"
+
+// Framebuffer renders the scene into a color texture at a fixed
+// native resolution, then draws that texture to the default
+// framebuffer through a scanline post-process shader. Drawing the
+// scene at a fixed resolution and upscaling once with a
+// nearest-neighbor texture keeps pixel art crisp across window
+// resizes, instead of every sprite being re-filtered at whatever size
+// the window happens to be.
+type Framebuffer struct {
+	fbo, texture     uint32
+	vao, vbo         uint32
+	program          uint32
+	textureLocation  int32
+	scanlineLocation int32
+	scanlineStrength float32
+	width, height    int32
+}
+
+// NewFramebuffer creates a color-texture-backed FBO at (width,
+// height) and compiles the post-process shader used to draw it.
+func NewFramebuffer(width, height int32) (*Framebuffer, error) {
+	fb := &Framebuffer{width: width, height: height, scanlineStrength: 0.06}
+
+	gl.GenFramebuffers(1, &fb.fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fb.fbo)
+
+	gl.GenTextures(1, &fb.texture)
+	gl.BindTexture(gl.TEXTURE_2D, fb.texture)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, width, height, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, fb.texture, 0)
+
+	err := checkFramebufferStatus()
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+
+	if err == nil {
+		fb.program, err = newPostProgram(postVertexShaderSource, postFragmentShaderSource)
+	}
+	if err == nil {
+		fb.textureLocation = gl.GetUniformLocation(fb.program, gl.Str("screenTexture\x00"))
+		fb.scanlineLocation = gl.GetUniformLocation(fb.program, gl.Str("scanlineStrength\x00"))
+		fb.bindQuad()
+	}
+	return fb, err
+}
+
+func checkFramebufferStatus() error {
+	if gl.CheckFramebufferStatus(gl.FRAMEBUFFER) != gl.FRAMEBUFFER_COMPLETE {
+		return errors.New("framebuffer incomplete")
+	}
+	return nil
+}
+
+func (fb *Framebuffer) bindQuad() {
+	vbos := newVBOs(1)
+	vaos := newVAOs(1)
+	fb.vao = vaos[0]
+	fb.vbo = vbos[0]
+	points := []float32{
+		// x, y, u, v
+		1, 1, 1, 1,
+		1, -1, 1, 0,
+		-1, 1, 0, 1,
+		-1, -1, 0, 0,
+	}
+
+	gl.BindVertexArray(fb.vao)
+	gl.EnableVertexAttribArray(0)
+	gl.EnableVertexAttribArray(1)
+	gl.BindBuffer(gl.ARRAY_BUFFER, fb.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(points)*4, gl.Ptr(points), gl.STATIC_DRAW)
+	gl.VertexAttribPointer(0, 2, gl.FLOAT, false, 4*4, gl.PtrOffset(0))
+	gl.VertexAttribPointer(1, 2, gl.FLOAT, false, 4*4, gl.PtrOffset(2*4))
+}
+
+// Bind directs subsequent draw calls into the offscreen texture at
+// its native resolution, regardless of the window's current size.
+func (fb *Framebuffer) Bind() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fb.fbo)
+	gl.Viewport(0, 0, fb.width, fb.height)
+}
+
+// Draw switches back to the default framebuffer and draws the
+// offscreen texture as a fullscreen quad, scaled to (width, height)
+// and run through the scanline shader.
+func (fb *Framebuffer) Draw(width, height int32) {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	gl.Viewport(0, 0, width, height)
+	gl.UseProgram(fb.program)
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, fb.texture)
+	gl.Uniform1i(fb.textureLocation, 0)
+	gl.Uniform1f(fb.scanlineLocation, fb.scanlineStrength)
+	gl.BindVertexArray(fb.vao)
+	gl.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
+}
+
+// Screenshot reads back the offscreen color texture and writes it to
+// path as a PNG, e.g. for a KeyF12 "save screenshot" binding.
+func (fb *Framebuffer) Screenshot(path string) error {
+	gl.BindTexture(gl.TEXTURE_2D, fb.texture)
+	return saveTexture(fb.width, fb.height, path)
+}
+
+// saveTexture reads back the currently bound 2D texture at (width,
+// height) and writes it to path as a PNG. GL's texture origin is
+// bottom-left while image/png expects top-left, so rows are flipped.
+func saveTexture(width, height int32, path string) error {
+	pixels := make([]uint8, width*height*4)
+	gl.GetTexImage(gl.TEXTURE_2D, 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(&pixels[0]))
+
+	img := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+	stride := int(width) * 4
+	for y := 0; y < int(height); y++ {
+		src := pixels[(int(height)-1-y)*stride : (int(height)-y)*stride]
+		copy(img.Pix[y*stride:(y+1)*stride], src)
+	}
+
+	file, err := os.Create(path)
+
+	if err == nil {
+		defer file.Close()
+		err = png.Encode(file, img)
+	}
+	return err
+}
+
+// newPostProgram compiles and links a vertex/fragment shader pair
+// that isn't backed by the shaders package, for the fixed
+// fullscreen-quad post-process effect.
+func newPostProgram(vertexSource, fragmentSource string) (uint32, error) {
+	vertexShader, err := compileShader(gl.VERTEX_SHADER, vertexSource)
+
+	if err == nil {
+		var fragmentShader uint32
+		fragmentShader, err = compileShader(gl.FRAGMENT_SHADER, fragmentSource)
+
+		if err == nil {
+			program := gl.CreateProgram()
+			gl.AttachShader(program, vertexShader)
+			gl.AttachShader(program, fragmentShader)
+			gl.LinkProgram(program)
+			err = checkProgram(program, gl.LINK_STATUS)
+
+			if err == nil {
+				gl.ValidateProgram(program)
+				err = checkProgram(program, gl.VALIDATE_STATUS)
+			}
+			if err != nil {
+				gl.DeleteProgram(program)
+			}
+			gl.DeleteShader(fragmentShader)
+			if err == nil {
+				gl.DeleteShader(vertexShader)
+				return program, nil
+			}
+		}
+		gl.DeleteShader(vertexShader)
+	}
+	return 0, err
+}
+
+func compileShader(shaderType uint32, source string) (uint32, error) {
+	shader := gl.CreateShader(shaderType)
+	csources, free := gl.Strs(source)
+	gl.ShaderSource(shader, 1, csources, nil)
+	free()
+	gl.CompileShader(shader)
+	err := checkShader(shader, gl.COMPILE_STATUS)
+
+	if err != nil {
+		gl.DeleteShader(shader)
+	}
+	return shader, err
+}
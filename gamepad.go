@@ -0,0 +1,138 @@
+/*
+ *          Copyright 2020, Vitali Baumtrok.
+ * Distributed under the Boost Software License, Version 1.0.
+ *     (See accompanying file LICENSE or copy at
+ *        http://www.boost.org/LICENSE_1_0.txt)
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+const (
+	axisDeadzone      = 0.2
+	gamepadConfigPath = "assets/gamepad.json"
+)
+
+// gamepadButtons maps actions to the joystick buttons that trigger
+// them, so users can rebind actions to different gamepad buttons
+// without recompiling (see loadGamepadButtons).
+type gamepadButtons struct {
+	Jump     int `json:"jump"`
+	WallLock int `json:"wallLock"`
+	Reset    int `json:"reset"`
+}
+
+var (
+	activeJoystick   = glfw.Joystick(-1)
+	buttons          = gamepadButtons{Jump: 0, WallLock: 1, Reset: 7}
+	wallLockHeld     bool
+	gamepadMoveLeft  bool
+	gamepadMoveRight bool
+	gamepadJump      bool
+)
+
+func init() {
+	glfw.SetJoystickCallback(onJoystickChange)
+
+	if remapped, err := loadGamepadButtons(gamepadConfigPath); err == nil {
+		buttons = remapped
+	}
+}
+
+// loadGamepadButtons reads a JSON button mapping from path, starting
+// from the current defaults so a file overriding only some actions
+// leaves the rest untouched. A missing or invalid file is not
+// fatal — the config is optional.
+func loadGamepadButtons(path string) (gamepadButtons, error) {
+	mapped := buttons
+	data, err := os.ReadFile(path)
+
+	if err == nil {
+		err = json.Unmarshal(data, &mapped)
+	}
+	return mapped, err
+}
+
+func onJoystickChange(joy glfw.Joystick, event glfw.PeripheralEvent) {
+	if event == glfw.Connected {
+		if activeJoystick < 0 && joy.Present() {
+			activeJoystick = joy
+			fmt.Println("gamepad connected:", joy.GetName())
+		}
+	} else if event == glfw.Disconnected {
+		if joy == activeJoystick {
+			fmt.Println("gamepad disconnected:", joy.GetName())
+			activeJoystick = -1
+		}
+	}
+}
+
+// pollGamepad reads the active joystick, if any, and maps its stick
+// and buttons onto their own gamepadMove*/gamepadJump state, then ORs
+// that together with the keyboard's keyMove*/keyJump state so either
+// input device can drive movement without the other silently
+// overriding it.
+func pollGamepad() {
+	if activeJoystick < 0 {
+		for joy := glfw.Joystick1; joy <= glfw.JoystickLast; joy++ {
+			if joy.Present() {
+				activeJoystick = joy
+				break
+			}
+		}
+	}
+
+	gamepadMoveLeft = false
+	gamepadMoveRight = false
+	gamepadJump = false
+
+	if activeJoystick >= 0 && activeJoystick.Present() {
+		axes := activeJoystick.GetAxes()
+		pressed := activeJoystick.GetButtons()
+
+		if len(axes) > 0 {
+			stickX := axes[0]
+			gamepadMoveLeft = stickX < -axisDeadzone
+			gamepadMoveRight = stickX > axisDeadzone
+		}
+		if buttonPressed(pressed, buttons.Jump) {
+			gamepadJump = true
+			if grounded || onWallLeft || onWallRight {
+				jumpingA = true
+				jumpY = playerY
+				jumpSpeed = jumpAcceleration
+			}
+		}
+		if buttonPressed(pressed, buttons.WallLock) {
+			if !wallLockHeld {
+				wallLock = !wallLock
+				if wallLock {
+					fmt.Println("wall lock ON")
+				} else {
+					fmt.Println("wall lock OFF")
+				}
+			}
+			wallLockHeld = true
+		} else {
+			wallLockHeld = false
+		}
+		if buttonPressed(pressed, buttons.Reset) {
+			resetPlayer()
+		}
+	}
+
+	moveLeft = keyMoveLeft || gamepadMoveLeft
+	moveRight = keyMoveRight || gamepadMoveRight
+	jump = keyJump || gamepadJump
+}
+
+func buttonPressed(states []glfw.Action, button int) bool {
+	return button >= 0 && button < len(states) && states[button] == glfw.Press
+}
@@ -0,0 +1,148 @@
+/*
+ *          Copyright 2020, Vitali Baumtrok.
+ * Distributed under the Boost Software License, Version 1.0.
+ *     (See accompanying file LICENSE or copy at
+ *        http://www.boost.org/LICENSE_1_0.txt)
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/vbsw/shaders"
+)
+
+// Solid is an axis-aligned collision box in level space. R, G, B, A
+// are used to draw it as a plain color quad unless Sprite is set, in
+// which case the sprite is drawn in its place instead.
+type Solid struct {
+	X, Y, W, H float32
+	R, G, B, A float32
+	Sprite     *Sprite
+	vao, vbo   uint32
+}
+
+// Level is an ordered list of solids loaded from a JSON file, each an
+// arbitrary AABB rather than a hand-picked platform/wall constant.
+type Level struct {
+	Solids []*Solid
+}
+
+type solidDef struct {
+	X      float32 `json:"x"`
+	Y      float32 `json:"y"`
+	W      float32 `json:"w"`
+	H      float32 `json:"h"`
+	R      float32 `json:"r"`
+	G      float32 `json:"g"`
+	B      float32 `json:"b"`
+	A      float32 `json:"a"`
+	Sprite string  `json:"sprite,omitempty"`
+}
+
+// LoadLevel reads a JSON array of solid definitions from path. A
+// solid without a sprite path is bound as a colored quad through
+// shader; one with a sprite path has its texture loaded and is drawn
+// through textureShader instead, with R, G, B, A ignored.
+func LoadLevel(path string, shader, textureShader *shaders.Shader) (*Level, error) {
+	data, err := os.ReadFile(path)
+
+	if err == nil {
+		var defs []solidDef
+		err = json.Unmarshal(data, &defs)
+
+		if err == nil {
+			level := &Level{Solids: make([]*Solid, len(defs))}
+			for i, def := range defs {
+				solid := &Solid{X: def.X, Y: def.Y, W: def.W, H: def.H, R: def.R, G: def.G, B: def.B, A: def.A}
+
+				if def.Sprite != "" {
+					var texture *Texture
+					texture, err = NewTexture(def.Sprite)
+
+					if err == nil {
+						solid.Sprite = NewSprite(textureShader, texture)
+					}
+				} else {
+					solid.bind(shader)
+				}
+				if err != nil {
+					return nil, err
+				}
+				level.Solids[i] = solid
+			}
+			return level, nil
+		}
+	}
+	return nil, err
+}
+
+func (s *Solid) bind(shader *shaders.Shader) {
+	vbos := newVBOs(1)
+	vaos := newVAOs(1)
+	s.vao = vaos[0]
+	s.vbo = vbos[0]
+	points := newColorPoints(s.X, s.Y, s.W, s.H, s.R, s.G, s.B, s.A)
+	bindObjects(s.vao, s.vbo, points, shader)
+}
+
+// draw renders the solid as a plain color quad. Solids with a Sprite
+// are drawn separately through the texture shader instead.
+func (s *Solid) draw(shader *shaders.Shader) {
+	modelMatrix[12] = 0.0
+	modelMatrix[13] = 0.0
+	gl.UniformMatrix4fv(shader.ModelLocation, 1, false, &modelMatrix[0])
+	gl.BindVertexArray(s.vao)
+	gl.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
+}
+
+// resolve pushes the player out of s along the axis of least
+// penetration, a generic swept-AABB routine that replaces the old
+// hard-coded checks against fixed platform/wall constants. Resolving
+// upward means the player landed on top of s, which sets the grounded
+// flag.
+func (s *Solid) resolve() {
+	left, right := s.X, s.X+s.W
+	bottom, top := s.Y, s.Y+s.H
+	pLeft, pRight := playerX, playerX+playerWidth
+	pBottom, pTop := playerY, playerY+playerHeight
+
+	overlapX := minF32(pRight, right) - maxF32(pLeft, left)
+	overlapY := minF32(pTop, top) - maxF32(pBottom, bottom)
+
+	if overlapX > 0 && overlapY > 0 {
+		if overlapX < overlapY {
+			if pLeft < left {
+				playerX -= overlapX
+				onWallRight = true
+			} else {
+				playerX += overlapX
+				onWallLeft = true
+			}
+		} else {
+			if pBottom < bottom {
+				playerY -= overlapY
+			} else {
+				playerY += overlapY
+				grounded = true
+			}
+		}
+	}
+}
+
+func minF32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxF32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
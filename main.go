@@ -14,6 +14,7 @@ import (
 	"github.com/go-gl/glfw/v3.3/glfw"
 	"github.com/vbsw/shaders"
 	"runtime"
+	"time"
 )
 
 const (
@@ -21,32 +22,45 @@ const (
 	canvasHeight     = 360
 	playerWidth      = 16
 	playerHeight     = 16
-	gapWidth         = 150
-	platformWidth    = canvasWidth - gapWidth
-	platformHeight   = 150
-	wallWidth        = 50
 	jumpHeightA      = 100
 	jumpHeightB      = 70
-	speedX           = 4
-	speedY           = 3
-	breakY           = 2
-	jumpAcceleration = 8
+	speedX           = 240 // px/s
+	speedY           = 180 // px/s
+	breakY           = 120 // px/s
+	jumpAcceleration = 480 // px/s^2
+	gravity          = 12  // px/s^2
+	fixedTimestep    = 1.0 / 120.0
 )
 
 var (
-	modelMatrix []float32
-	moveLeft    bool
-	moveRight   bool
-	moveUp      bool
-	moveDown    bool
-	jump        bool
-	jumpingA    bool
-	jumpingB    bool
-	wallLock    bool
-	playerX     float32
-	playerY     float32
-	jumpY       float32
-	jumpSpeed   float32
+	modelMatrix  []float32
+	moveLeft     bool
+	moveRight    bool
+	moveUp       bool
+	moveDown     bool
+	jump         bool
+	keyMoveLeft  bool
+	keyMoveRight bool
+	keyJump      bool
+	jumpingA     bool
+	jumpingB     bool
+	wallLock     bool
+	playerX      float32
+	playerY      float32
+	prevPlayerX  float32
+	prevPlayerY  float32
+	jumpY        float32
+	jumpSpeed    float32
+	grounded     bool
+	onWallLeft   bool
+	onWallRight  bool
+	windowWidth  = canvasWidth
+	windowHeight = canvasHeight
+	followCam    *Camera
+	minimapCam   *Camera
+	viewports    []*Viewport
+	level        *Level
+	framebuffer  *Framebuffer
 )
 
 func init() {
@@ -77,45 +91,103 @@ func main() {
 					defer gl.DeleteShader(shader.VertexShaderID)
 					defer gl.DeleteShader(shader.FragmentShaderID)
 					defer gl.DeleteProgram(shader.ProgramID)
-					vbos := newVBOs(3)
-					defer gl.DeleteBuffers(int32(len(vbos)), &vbos[0])
-					vaos := newVAOs(3)
-					defer gl.DeleteVertexArrays(int32(len(vaos)), &vaos[0])
-					modelMatrix = newModelMatrix()
-
-					bindLevelObjects(shader, vaos[:2], vbos[:2])
-					bindPlayerObjects(shader, vaos[2:], vbos[2:])
-					gl.UseProgram(shader.ProgramID)
-					setProjection(shader)
-					resetPlayer()
-
-					// transparancy
-					// gl.Enable(gl.BLEND);
-					// gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA);
-
-					// wireframe mode
-					// gl.PolygonMode(gl.FRONT_AND_BACK, gl.LINE)
-
-					for !window.ShouldClose() {
-						updateMovement()
-						gl.ClearColor(0, 0, 0, 0)
-						gl.Clear(gl.COLOR_BUFFER_BIT)
-
-						// draw level
-						setLevelModel(shader)
-						for _, vao := range vaos[:2] {
-							gl.BindVertexArray(vao)
-							gl.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
+					textureShader := shaders.NewTextureShader()
+					err = initTextureShaderProgram(textureShader)
+
+					if err == nil {
+						defer gl.DeleteShader(textureShader.VertexShaderID)
+						defer gl.DeleteShader(textureShader.FragmentShaderID)
+						defer gl.DeleteProgram(textureShader.ProgramID)
+						modelMatrix = newModelMatrix()
+
+						framebuffer, err = NewFramebuffer(canvasWidth, canvasHeight)
+
+						if err == nil {
+							defer gl.DeleteFramebuffers(1, &framebuffer.fbo)
+							defer gl.DeleteTextures(1, &framebuffer.texture)
+							defer gl.DeleteProgram(framebuffer.program)
+
+							level, err = LoadLevel("assets/level.json", shader, textureShader)
+
+							if err == nil {
+								gl.UseProgram(textureShader.ProgramID)
+								var playerTexture *Texture
+								playerTexture, err = NewTexture("assets/player.png")
+
+								if err == nil {
+									playerSprite := NewSprite(textureShader, playerTexture)
+									resetPlayer()
+									prevPlayerX = playerX
+									prevPlayerY = playerY
+									followCam = NewCamera(playerX, playerY, 1)
+									minimapCam = NewCamera(canvasWidth/2, canvasHeight/2, 0.25)
+									updateViewports()
+
+									gl.Enable(gl.BLEND)
+									gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+
+									// wireframe mode
+									// gl.PolygonMode(gl.FRONT_AND_BACK, gl.LINE)
+
+									prevTime := glfw.GetTime()
+									var accumulator float64
+
+									for !window.ShouldClose() {
+										currentTime := glfw.GetTime()
+										frameTime := currentTime - prevTime
+										prevTime = currentTime
+										if frameTime > 0.25 {
+											// clamp so a stall (breakpoint, window drag) doesn't
+											// trigger a spiral of death of catch-up steps
+											frameTime = 0.25
+										}
+										accumulator += frameTime
+
+										pollGamepad()
+										for accumulator >= fixedTimestep {
+											prevPlayerX = playerX
+											prevPlayerY = playerY
+											updateMovement(fixedTimestep)
+											accumulator -= fixedTimestep
+										}
+
+										alpha := float32(accumulator / fixedTimestep)
+										interpPlayerX := prevPlayerX + (playerX-prevPlayerX)*alpha
+										interpPlayerY := prevPlayerY + (playerY-prevPlayerY)*alpha
+										followCam.X = interpPlayerX + playerWidth/2
+										followCam.Y = interpPlayerY + playerHeight/2
+
+										framebuffer.Bind()
+										gl.ClearColor(0, 0, 0, 0)
+										gl.Clear(gl.COLOR_BUFFER_BIT)
+
+										for _, viewport := range viewports {
+											// draw color-only solids
+											gl.UseProgram(shader.ProgramID)
+											viewport.bind(shader)
+											for _, solid := range level.Solids {
+												if solid.Sprite == nil {
+													solid.draw(shader)
+												}
+											}
+
+											// draw sprite-backed solids and the player
+											gl.UseProgram(textureShader.ProgramID)
+											viewport.bind(textureShader)
+											for _, solid := range level.Solids {
+												if solid.Sprite != nil {
+													solid.Sprite.Draw(textureShader, solid.X, solid.Y, solid.W, solid.H)
+												}
+											}
+											playerSprite.Draw(textureShader, interpPlayerX, interpPlayerY, playerWidth, playerHeight)
+										}
+										framebuffer.Draw(int32(windowWidth), int32(windowHeight))
+										window.SwapBuffers()
+										glfw.PollEvents()
+									}
+								}
+							}
 						}
-
-						// draw player
-						setPlayerModel(shader)
-						for _, vao := range vaos[2:] {
-							gl.BindVertexArray(vao)
-							gl.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
-						}
-						window.SwapBuffers()
-						glfw.PollEvents()
 					}
 				}
 			}
@@ -132,13 +204,13 @@ func onKey(window *glfw.Window, key glfw.Key, scancode int, action glfw.Action,
 		case glfw.KeyEscape:
 			window.SetShouldClose(true)
 		case glfw.KeyLeft:
-			moveLeft = true
+			keyMoveLeft = true
 		case glfw.KeyJ:
-			moveLeft = true
+			keyMoveLeft = true
 		case glfw.KeyRight:
-			moveRight = true
+			keyMoveRight = true
 		case glfw.KeyL:
-			moveRight = true
+			keyMoveRight = true
 		case glfw.KeyUp:
 			moveUp = true
 		case glfw.KeyI:
@@ -155,32 +227,41 @@ func onKey(window *glfw.Window, key glfw.Key, scancode int, action glfw.Action,
 				fmt.Println("wall lock OFF")
 			}
 		case glfw.KeySpace:
-			jump = true
-			if playerY <= jumpY && playerX < platformWidth || (playerX == platformWidth || playerX == canvasWidth-wallWidth-playerWidth) {
+			keyJump = true
+			if grounded || onWallLeft || onWallRight {
 				jumpingA = true
 				jumpY = playerY
 				jumpSpeed = jumpAcceleration
 			}
 		case glfw.KeyF:
-			jump = true
-			if playerY <= jumpY && playerX < platformWidth || (playerX == platformWidth || playerX == canvasWidth-wallWidth-playerWidth) {
+			keyJump = true
+			if grounded || onWallLeft || onWallRight {
 				jumpingA = true
 				jumpY = playerY
 				jumpSpeed = jumpAcceleration
 			}
 		case glfw.KeyR:
 			resetPlayer()
+		case glfw.KeyF12:
+			path := fmt.Sprintf("screenshot-%d.png", time.Now().Unix())
+			err := framebuffer.Screenshot(path)
+
+			if err == nil {
+				fmt.Println("saved " + path)
+			} else {
+				fmt.Println(err.Error())
+			}
 		}
 	} else if action == glfw.Release {
 		switch key {
 		case glfw.KeyLeft:
-			moveLeft = false
+			keyMoveLeft = false
 		case glfw.KeyJ:
-			moveLeft = false
+			keyMoveLeft = false
 		case glfw.KeyRight:
-			moveRight = false
+			keyMoveRight = false
 		case glfw.KeyL:
-			moveRight = false
+			keyMoveRight = false
 		case glfw.KeyUp:
 			moveUp = false
 		case glfw.KeyI:
@@ -190,15 +271,32 @@ func onKey(window *glfw.Window, key glfw.Key, scancode int, action glfw.Action,
 		case glfw.KeyK:
 			moveDown = false
 		case glfw.KeySpace:
-			jump = false
+			keyJump = false
 		case glfw.KeyF:
-			jump = false
+			keyJump = false
 		}
 	}
 }
 
+// onResize only tracks the window's current size for the final
+// post-process blit (see Framebuffer.Draw); the viewports themselves
+// are laid out against the fixed canvas resolution and don't change.
 func onResize(w *glfw.Window, width, height int) {
-	gl.Viewport(0, 0, int32(width), int32(height))
+	windowWidth = width
+	windowHeight = height
+}
+
+// updateViewports lays out the main (follow camera) viewport over the
+// whole canvas and a minimap viewport pinned to its top-right corner.
+// Both are rendered into the framebuffer at its fixed native
+// resolution, not the window's current size.
+func updateViewports() {
+	const minimapWidth = 180
+	const minimapHeight = 100
+	viewports = []*Viewport{
+		NewViewport(0, 0, canvasWidth, canvasHeight, followCam),
+		NewViewport(canvasWidth-minimapWidth, canvasHeight-minimapHeight, minimapWidth, minimapHeight, minimapCam),
+	}
 }
 
 func initShaderProgram(shader *shaders.Shader) error {
@@ -313,27 +411,6 @@ func newVAOs(n int) []uint32 {
 	return vaos
 }
 
-func bindLevelObjects(shader *shaders.Shader, vaos, vbos []uint32) {
-	pointsA := newPoints(0, 0, platformWidth, platformHeight)
-	pointsB := newPoints(canvasWidth-wallWidth, 0, wallWidth, 340)
-	bindObjects(vaos[0], vbos[0], pointsA, shader)
-	bindObjects(vaos[1], vbos[1], pointsB, shader)
-}
-
-func bindPlayerObjects(shader *shaders.Shader, vaos, vbos []uint32) {
-	pointsA := newPoints(0, 0, playerWidth, playerHeight)
-	// green color
-	pointsA[3] = 0.0
-	pointsA[5] = 0.0
-	pointsA[10] = 0.0
-	pointsA[12] = 0.0
-	pointsA[17] = 0.0
-	pointsA[19] = 0.0
-	pointsA[24] = 0.0
-	pointsA[26] = 0.0
-	bindObjects(vaos[0], vbos[0], pointsA, shader)
-}
-
 func bindObjects(vao, vbo uint32, points []float32, shader *shaders.Shader) {
 	gl.BindVertexArray(vao)
 	gl.EnableVertexAttribArray(uint32(shader.PositionLocation))
@@ -347,36 +424,36 @@ func bindObjects(vao, vbo uint32, points []float32, shader *shaders.Shader) {
 	gl.VertexAttribPointer(uint32(shader.ColorLocation), 4, gl.FLOAT, false, 7*4, gl.PtrOffset(3*4))
 }
 
-func newPoints(aX, aY, width, height float32) []float32 {
+func newColorPoints(aX, aY, width, height, r, g, b, a float32) []float32 {
 	points := make([]float32, 28)
 	points[0] = aX + width
 	points[1] = aY + height
 	points[2] = 0.0
-	points[3] = 1.0
-	points[4] = 1.0
-	points[5] = 1.0
-	points[6] = 1.0
+	points[3] = r
+	points[4] = g
+	points[5] = b
+	points[6] = a
 	points[7] = aX + width
 	points[8] = aY
 	points[9] = 0.0
-	points[10] = 1.0
-	points[11] = 1.0
-	points[12] = 1.0
-	points[13] = 1.0
+	points[10] = r
+	points[11] = g
+	points[12] = b
+	points[13] = a
 	points[14] = aX
 	points[15] = aY + height
 	points[16] = 0.0
-	points[17] = 1.0
-	points[18] = 1.0
-	points[19] = 1.0
-	points[20] = 1.0
+	points[17] = r
+	points[18] = g
+	points[19] = b
+	points[20] = a
 	points[21] = aX
 	points[22] = aY
 	points[23] = 0.0
-	points[24] = 1.0
-	points[25] = 1.0
-	points[26] = 1.0
-	points[27] = 1.0
+	points[24] = r
+	points[25] = g
+	points[26] = b
+	points[27] = a
 	return points
 }
 
@@ -389,91 +466,67 @@ func newModelMatrix() []float32 {
 	return modelMatrix
 }
 
-func setProjection(shader *shaders.Shader) {
-	matrix := make([]float32, 4*4)
-	matrix[0] = 2.0 / float32(canvasWidth)
-	matrix[5] = 2.0 / float32(canvasHeight)
-	matrix[12] = -1.0
-	matrix[13] = -1.0
-	matrix[15] = 1.0
-	gl.UniformMatrix4fv(shader.ProjectionLocation, 1, false, &matrix[0])
-}
-
-func setLevelModel(shader *shaders.Shader) {
-	modelMatrix[12] = 0.0
-	modelMatrix[13] = 0.0
-	gl.UniformMatrix4fv(shader.ModelLocation, 1, false, &modelMatrix[0])
-}
-
-func setPlayerModel(shader *shaders.Shader) {
-	modelMatrix[12] = playerX
-	modelMatrix[13] = playerY
-	gl.UniformMatrix4fv(shader.ModelLocation, 1, false, &modelMatrix[0])
-}
+// updateMovement advances the simulation by dt seconds. It is called
+// at a fixed timestep (see the accumulator loop in main) so that jump
+// height and horizontal speed don't depend on the render frame rate.
+// Collision against the level is resolved generically at the end
+// against whatever solids are in level.Solids, so grounded/onWallLeft/
+// onWallRight reflect the previous frame's resolve and are what the
+// wall-lock and wall-jump checks below act on.
+func updateMovement(dt float32) {
+	wasOnWallLeft := onWallLeft
+	wasOnWallRight := onWallRight
 
-func updateMovement() {
 	if jumpingA {
 		if playerY-jumpY < jumpHeightA {
-			playerY += jumpSpeed
-			jumpSpeed -= 0.2
+			playerY += jumpSpeed * dt
+			jumpSpeed -= gravity * dt
 			if playerY-jumpY < jumpHeightB && !jump {
 				jumpingA = false
 				jumpingB = true
 			}
 		} else {
 			jumpingA = false
-			playerY += -speedY
+			playerY += -speedY * dt
 		}
 	} else if jumpingB {
 		if playerY-jumpY < jumpHeightB {
-			playerY += jumpSpeed
-			jumpSpeed -= 0.2
+			playerY += jumpSpeed * dt
+			jumpSpeed -= gravity * dt
 		} else {
 			jumpingB = false
-			playerY += -speedY
+			playerY += -speedY * dt
 		}
-	} else if playerY > platformHeight || playerX >= platformWidth {
-		playerY += -speedY
-		if playerX < platformWidth && playerY < platformHeight {
-			playerY = platformHeight
-			jumpY = platformHeight
-		}
-	} else if playerX > platformWidth {
-		playerY += -speedY
+	} else {
+		playerY += -speedY * dt
 	}
 	if moveLeft {
-		if wallLock {
-			if jump || playerX != canvasWidth-wallWidth-playerWidth || moveDown {
-				playerX += -speedX
-			} else {
-				playerY += breakY
-			}
+		if wallLock && wasOnWallRight && !jump && !moveDown {
+			playerY += breakY * dt
 		} else {
-			playerX += -speedX
-		}
-		if playerX < platformWidth && playerY < platformHeight {
-			playerX = platformWidth
-			playerY += breakY
+			playerX += -speedX * dt
 		}
 	} else if moveRight {
-		if wallLock {
-			if jump || playerX != platformWidth || moveDown {
-				playerX += speedX
-			} else {
-				playerY += breakY
-			}
+		if wallLock && wasOnWallLeft && !jump && !moveDown {
+			playerY += breakY * dt
 		} else {
-			playerX += speedX
-		}
-		if playerX > canvasWidth-wallWidth-playerWidth {
-			playerX = canvasWidth - wallWidth - playerWidth
-			playerY += breakY
+			playerX += speedX * dt
 		}
 	}
+
+	grounded = false
+	onWallLeft = false
+	onWallRight = false
+	for _, solid := range level.Solids {
+		solid.resolve()
+	}
+	if grounded {
+		jumpY = playerY
+	}
 }
 
 func resetPlayer() {
 	playerX = (canvasWidth-150)/2 + playerWidth/2
 	playerY = canvasHeight - canvasHeight/3
-	jumpY = platformHeight
+	jumpY = playerY
 }
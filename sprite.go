@@ -0,0 +1,124 @@
+/*
+ *          Copyright 2020, Vitali Baumtrok.
+ * Distributed under the Boost Software License, Version 1.0.
+ *     (See accompanying file LICENSE or copy at
+ *        http://www.boost.org/LICENSE_1_0.txt)
+ */
+
+package main
+
+import (
+	"image"
+	"image/draw"
+	_ "image/png"
+	"os"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/vbsw/shaders"
+)
+
+// Texture is a PNG image uploaded to the GPU as a mipmapped 2D
+// texture, ready to be sampled by the texture shader.
+type Texture struct {
+	ID            uint32
+	Width, Height int32
+}
+
+// NewTexture loads the PNG file at path and uploads it.
+func NewTexture(path string) (*Texture, error) {
+	file, err := os.Open(path)
+
+	if err == nil {
+		defer file.Close()
+		var img image.Image
+		img, _, err = image.Decode(file)
+
+		if err == nil {
+			bounds := img.Bounds()
+			rgba := image.NewRGBA(bounds)
+			draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
+
+			var id uint32
+			gl.GenTextures(1, &id)
+			gl.BindTexture(gl.TEXTURE_2D, id)
+			gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+			gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+			gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+			gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+			gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, int32(bounds.Dx()), int32(bounds.Dy()), 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(rgba.Pix))
+			gl.GenerateMipmap(gl.TEXTURE_2D)
+
+			return &Texture{ID: id, Width: int32(bounds.Dx()), Height: int32(bounds.Dy())}, nil
+		}
+	}
+	return nil, err
+}
+
+func (t *Texture) bind(unit uint32) {
+	gl.ActiveTexture(gl.TEXTURE0 + unit)
+	gl.BindTexture(gl.TEXTURE_2D, t.ID)
+}
+
+// Sprite is a textured, alpha-blended quad, drawn at an arbitrary
+// (x, y, w, h) through the same model matrix the primitive pipeline
+// uses, scaled and translated to fit.
+type Sprite struct {
+	vao, vbo uint32
+	texture  *Texture
+}
+
+// NewSprite uploads a unit quad with interleaved position and texture
+// coordinates, sampling texture when drawn.
+func NewSprite(shader *shaders.Shader, texture *Texture) *Sprite {
+	vbos := newVBOs(1)
+	vaos := newVAOs(1)
+	sprite := &Sprite{vao: vaos[0], vbo: vbos[0], texture: texture}
+	points := []float32{
+		// x, y, z, u, v
+		1, 1, 0, 1, 0,
+		1, 0, 0, 1, 1,
+		0, 1, 0, 0, 0,
+		0, 0, 0, 0, 1,
+	}
+
+	gl.BindVertexArray(sprite.vao)
+	gl.EnableVertexAttribArray(uint32(shader.PositionLocation))
+	gl.EnableVertexAttribArray(uint32(shader.CoordsLocation))
+	gl.BindBuffer(gl.ARRAY_BUFFER, sprite.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(points)*4, gl.Ptr(points), gl.STATIC_DRAW)
+	// position
+	gl.VertexAttribPointer(uint32(shader.PositionLocation), 3, gl.FLOAT, false, 5*4, gl.PtrOffset(0))
+	// texture coordinate
+	gl.VertexAttribPointer(uint32(shader.CoordsLocation), 2, gl.FLOAT, false, 5*4, gl.PtrOffset(3*4))
+	return sprite
+}
+
+// Draw renders the sprite at (x, y), scaled to (w, h).
+func (s *Sprite) Draw(shader *shaders.Shader, x, y, w, h float32) {
+	s.texture.bind(0)
+	gl.Uniform1i(shader.TextureLocation, 0)
+
+	modelMatrix[0] = w
+	modelMatrix[5] = h
+	modelMatrix[12] = x
+	modelMatrix[13] = y
+	gl.UniformMatrix4fv(shader.ModelLocation, 1, false, &modelMatrix[0])
+	modelMatrix[0] = 1.0
+	modelMatrix[5] = 1.0
+
+	gl.BindVertexArray(s.vao)
+	gl.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
+}
+
+// initTextureShaderProgram compiles and links the texture shader,
+// resolving the extra texcoord/sampler locations it needs on top of
+// the attributes/uniforms shared with the primitive shader.
+func initTextureShaderProgram(shader *shaders.Shader) error {
+	err := initShaderProgram(shader)
+
+	if err == nil {
+		shader.CoordsLocation = gl.GetAttribLocation(shader.ProgramID, shader.CoordsAttribute)
+		shader.TextureLocation = gl.GetUniformLocation(shader.ProgramID, shader.TextureUniform)
+	}
+	return err
+}